@@ -0,0 +1,211 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	envCacheEnabled     = "TXTWEB_CACHE_ENABLED"
+	envCacheMinTTL      = "TXTWEB_CACHE_MIN_TTL"
+	envCacheMaxTTL      = "TXTWEB_CACHE_MAX_TTL"
+	envCacheNegativeTTL = "TXTWEB_CACHE_NEGATIVE_TTL"
+	envCacheMaxEntries  = "TXTWEB_CACHE_MAX_ENTRIES"
+
+	defaultCacheMinTTL      = 5 * time.Second
+	defaultCacheMaxTTL      = 10 * time.Minute
+	defaultCacheNegativeTTL = 10 * time.Second
+	defaultCacheMaxEntries  = 10000
+
+	// fallbackTTL is used when the wrapped resolver can't report a TTL.
+	fallbackTTL = 60 * time.Second
+)
+
+// cacheConfig holds the caching knobs txtweb reads from the environment.
+type cacheConfig struct {
+	enabled     bool
+	minTTL      time.Duration
+	maxTTL      time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+}
+
+func loadCacheConfigFromEnv() cacheConfig {
+	return cacheConfig{
+		enabled:     envBool(envCacheEnabled, false),
+		minTTL:      envDuration(envCacheMinTTL, defaultCacheMinTTL),
+		maxTTL:      envDuration(envCacheMaxTTL, defaultCacheMaxTTL),
+		negativeTTL: envDuration(envCacheNegativeTTL, defaultCacheNegativeTTL),
+		maxEntries:  envInt(envCacheMaxEntries, defaultCacheMaxEntries),
+	}
+}
+
+func (c cacheConfig) clamp(ttl time.Duration) time.Duration {
+	if ttl < c.minTTL {
+		return c.minTTL
+	}
+	if ttl > c.maxTTL {
+		return c.maxTTL
+	}
+	return ttl
+}
+
+// ttlTXTResolver is implemented by resolvers that can report how long their
+// answer may be cached for, such as the miekg/dns-backed resolver. Resolvers
+// that don't implement it (e.g. net.Resolver) fall back to fallbackTTL.
+type ttlTXTResolver interface {
+	LookupTXTWithTTL(ctx context.Context, host string) ([]string, time.Duration, error)
+}
+
+type cacheEntry struct {
+	records   []string
+	err       error
+	expiresAt time.Time
+}
+
+// cacheBackend is the pluggable storage for resolved TXT lookups. The
+// default implementation is an in-memory LRU; operators wanting a shared
+// cache across instances can swap in a Redis-backed implementation without
+// touching cachingResolver.
+type cacheBackend interface {
+	get(key string) (cacheEntry, bool)
+	set(key string, entry cacheEntry)
+}
+
+// lruCache is a fixed-size, mutex-protected in-memory cacheBackend.
+type lruCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry cacheEntry
+}
+
+func newLRUCache(maxEntries int) *lruCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+	return &lruCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	entry := el.Value.(*lruItem).entry
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return cacheEntry{}, false
+	}
+
+	c.ll.MoveToFront(el)
+	return entry, true
+}
+
+func (c *lruCache) set(key string, entry cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruItem).entry = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(&lruItem{key: key, entry: entry})
+	c.items[key] = el
+
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+// cachingResolver wraps a txtResolver with a TTL-aware cache, deduplicating
+// concurrent lookups for the same name via singleflight. Failed lookups and
+// empty answers (NXDOMAIN included) are negative-cached for cfg.negativeTTL
+// rather than for the clamped answer TTL that successful ones get.
+type cachingResolver struct {
+	resolver txtResolver
+	cfg      cacheConfig
+	backend  cacheBackend
+	group    singleflight.Group
+}
+
+func newCachingResolver(resolver txtResolver, cfg cacheConfig) *cachingResolver {
+	return &cachingResolver{
+		resolver: resolver,
+		cfg:      cfg,
+		backend:  newLRUCache(cfg.maxEntries),
+	}
+}
+
+func (r *cachingResolver) LookupTXT(ctx context.Context, host string) ([]string, error) {
+	if entry, ok := r.backend.get(host); ok {
+		cacheHitsTotal.Inc()
+		observerFromContext(ctx).recordCacheResult(true)
+		return entry.records, entry.err
+	}
+
+	cacheMissesTotal.Inc()
+	observerFromContext(ctx).recordCacheResult(false)
+
+	v, err, _ := r.group.Do(host, func() (interface{}, error) {
+		// Another caller's lookup may have finished and been cached since
+		// the backend.get above; reuse it instead of querying again.
+		if entry, ok := r.backend.get(host); ok {
+			return entry.records, entry.err
+		}
+
+		records, ttl, lookupErr := r.lookup(ctx, host)
+
+		expiry := r.cfg.negativeTTL
+		if lookupErr == nil && len(records) > 0 {
+			expiry = r.cfg.clamp(ttl)
+		}
+
+		r.backend.set(host, cacheEntry{
+			records:   records,
+			err:       lookupErr,
+			expiresAt: time.Now().Add(expiry),
+		})
+
+		return records, lookupErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]string), nil
+}
+
+func (r *cachingResolver) lookup(ctx context.Context, host string) ([]string, time.Duration, error) {
+	if ttlResolver, ok := r.resolver.(ttlTXTResolver); ok {
+		return ttlResolver.LookupTXTWithTTL(ctx, host)
+	}
+
+	records, err := r.resolver.LookupTXT(ctx, host)
+	return records, fallbackTTL, err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheConfigClamp(t *testing.T) {
+	cfg := cacheConfig{minTTL: 5 * time.Second, maxTTL: 10 * time.Minute}
+
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{"below min", time.Second, 5 * time.Second},
+		{"above max", time.Hour, 10 * time.Minute},
+		{"within range", time.Minute, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.clamp(tt.ttl); got != tt.want {
+				t.Fatalf("clamp(%v) = %v, want %v", tt.ttl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLRUCacheEvictsOldestWhenOverCapacity(t *testing.T) {
+	c := newLRUCache(2)
+	future := time.Now().Add(time.Minute)
+
+	c.set("a", cacheEntry{records: []string{"a"}, expiresAt: future})
+	c.set("b", cacheEntry{records: []string{"b"}, expiresAt: future})
+	c.set("c", cacheEntry{records: []string{"c"}, expiresAt: future})
+
+	if _, ok := c.get("a"); ok {
+		t.Fatal("expected oldest entry \"a\" to have been evicted")
+	}
+	if _, ok := c.get("b"); !ok {
+		t.Fatal("expected \"b\" to still be cached")
+	}
+	if _, ok := c.get("c"); !ok {
+		t.Fatal("expected \"c\" to still be cached")
+	}
+}
+
+func TestLRUCacheGetRefreshesRecency(t *testing.T) {
+	c := newLRUCache(2)
+	future := time.Now().Add(time.Minute)
+
+	c.set("a", cacheEntry{records: []string{"a"}, expiresAt: future})
+	c.set("b", cacheEntry{records: []string{"b"}, expiresAt: future})
+	c.get("a") // touch "a" so "b" becomes the oldest
+	c.set("c", cacheEntry{records: []string{"c"}, expiresAt: future})
+
+	if _, ok := c.get("b"); ok {
+		t.Fatal("expected \"b\" to have been evicted as the least recently used entry")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("expected \"a\" to still be cached after being touched")
+	}
+}
+
+func TestLRUCacheGetExpiresEntries(t *testing.T) {
+	c := newLRUCache(10)
+	c.set("a", cacheEntry{records: []string{"a"}, expiresAt: time.Now().Add(-time.Second)})
+
+	if _, ok := c.get("a"); ok {
+		t.Fatal("expected expired entry to be treated as a miss")
+	}
+}
+
+type fakeTTLResolver struct {
+	mu     sync.Mutex
+	calls  int32
+	ttl    time.Duration
+	err    error
+	record string
+}
+
+func (f *fakeTTLResolver) LookupTXT(ctx context.Context, host string) ([]string, error) {
+	records, _, err := f.LookupTXTWithTTL(ctx, host)
+	return records, err
+}
+
+func (f *fakeTTLResolver) LookupTXTWithTTL(_ context.Context, _ string) ([]string, time.Duration, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	return []string{f.record}, f.ttl, nil
+}
+
+func TestCachingResolverDedupsConcurrentLookups(t *testing.T) {
+	resolver := &fakeTTLResolver{ttl: time.Minute, record: "hello"}
+	r := newCachingResolver(resolver, cacheConfig{minTTL: time.Second, maxTTL: time.Hour, negativeTTL: time.Second, maxEntries: 10})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			records, err := r.LookupTXT(context.Background(), "example.com")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if len(records) != 1 || records[0] != "hello" {
+				t.Errorf("unexpected records: %v", records)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&resolver.calls); calls != 1 {
+		t.Fatalf("expected exactly 1 upstream lookup, got %d", calls)
+	}
+}
+
+func TestCachingResolverNegativeCachesErrors(t *testing.T) {
+	wantErr := errors.New("lookup failed")
+	resolver := &fakeTTLResolver{err: wantErr}
+	r := newCachingResolver(resolver, cacheConfig{minTTL: time.Second, maxTTL: time.Hour, negativeTTL: time.Minute, maxEntries: 10})
+
+	if _, err := r.LookupTXT(context.Background(), "example.com"); !errors.Is(err, wantErr) {
+		t.Fatalf("expected error %v, got %v", wantErr, err)
+	}
+	if _, err := r.LookupTXT(context.Background(), "example.com"); !errors.Is(err, wantErr) {
+		t.Fatalf("expected cached error %v, got %v", wantErr, err)
+	}
+	if calls := atomic.LoadInt32(&resolver.calls); calls != 1 {
+		t.Fatalf("expected the error to be served from cache on the second call, got %d upstream calls", calls)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	chunksConfigKey = "chunks"
+	maxChunkCount   = 50
+
+	envMaxBodyBytes     = "TXTWEB_MAX_BODY_BYTES"
+	defaultMaxBodyBytes = 1 << 20 // 1 MiB
+)
+
+// chunkOrderPattern matches an optional "NN|" ordering prefix on a TXT
+// record, e.g. "01|<h1>...".
+var chunkOrderPattern = regexp.MustCompile(`^([0-9]+)\|(.*)$`)
+
+// orderRecords sorts records by their "NN|" prefix and strips it, so
+// operators can control ordering when their DNS provider returns TXT
+// strings in arbitrary order. If any record in the set is missing the
+// prefix, the original order is preserved untouched.
+func orderRecords(records []string) []string {
+	type numbered struct {
+		n     int
+		value string
+	}
+
+	numberedRecords := make([]numbered, len(records))
+	for i, record := range records {
+		m := chunkOrderPattern.FindStringSubmatch(record)
+		if m == nil {
+			return records
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return records
+		}
+		numberedRecords[i] = numbered{n: n, value: m[2]}
+	}
+
+	sort.SliceStable(numberedRecords, func(i, j int) bool {
+		return numberedRecords[i].n < numberedRecords[j].n
+	})
+
+	ordered := make([]string, len(numberedRecords))
+	for i, nr := range numberedRecords {
+		ordered[i] = nr.value
+	}
+	return ordered
+}
+
+// parseChunkCount reads the "chunks=N" config value, clamped to
+// maxChunkCount so a misbehaving or malicious config can't fan out an
+// unbounded number of DNS lookups per request.
+func parseChunkCount(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	if n > maxChunkCount {
+		return maxChunkCount
+	}
+	return n
+}
+
+type chunkResult struct {
+	records []string
+	err     error
+}
+
+// fetchChunks queries "_txtweb1.<hostSuffix>" through "_txtwebN.<hostSuffix>"
+// in parallel and returns their TXT records in chunk order, so large sites
+// can be split across multiple record sets. hostSuffix is the plain
+// hostname for the root site, or a path-qualified suffix (see
+// pathsQualifiedHost) so a per-path page's chunks don't bleed into other
+// paths.
+func fetchChunks(ctx context.Context, resolver txtResolver, hostSuffix string, n int) ([][]string, error) {
+	results := make([]chunkResult, n)
+
+	done := make(chan int, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			records, err := resolveTXTRecord(ctx, resolver, txtwebRecord+strconv.Itoa(i+1)+"."+hostSuffix)
+			results[i] = chunkResult{records: records, err: err}
+			done <- i
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		<-done
+	}
+
+	chunks := make([][]string, n)
+	for i, result := range results {
+		if result.err != nil {
+			return nil, result.err
+		}
+		chunks[i] = result.records
+	}
+	return chunks, nil
+}
+
+func maxBodyBytes() int {
+	return envInt(envMaxBodyBytes, defaultMaxBodyBytes)
+}
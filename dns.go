@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const envDNSServer = "TXTWEB_DNS_SERVER"
+
+// dnsResolver is a txtResolver/ttlTXTResolver implementation backed by
+// miekg/dns, used in place of net.Resolver when the cache wants real TTLs
+// to key its expiry off (net.Resolver.LookupTXT doesn't expose them).
+type dnsResolver struct {
+	server string
+	client *dns.Client
+}
+
+func newDNSResolver(server string) *dnsResolver {
+	return &dnsResolver{
+		server: server,
+		client: &dns.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (r *dnsResolver) LookupTXT(ctx context.Context, host string) ([]string, error) {
+	records, _, err := r.LookupTXTWithTTL(ctx, host)
+	return records, err
+}
+
+func (r *dnsResolver) LookupTXTWithTTL(ctx context.Context, host string) ([]string, time.Duration, error) {
+	in, err := exchangeTXT(ctx, r.client, r.server, host, false)
+	if err != nil || in == nil {
+		return nil, 0, err
+	}
+	return txtRecordsAndTTL(in)
+}
+
+// exchangeTXT sends a TXT query for host to server, requesting DNSSEC
+// records (the DO bit) when dnssec is true. It always advertises a 4096-byte
+// EDNS0 buffer -- not just for DNSSEC queries, since TXT answers routinely
+// exceed the classic 512-byte UDP limit (see chunks.go) -- and retries over
+// TCP if the server truncates the response anyway. It returns a nil message
+// without error for NXDOMAIN, and an error for any other non-success
+// response.
+func exchangeTXT(ctx context.Context, client *dns.Client, server, host string, dnssec bool) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(host), dns.TypeTXT)
+	msg.RecursionDesired = true
+	msg.SetEdns0(4096, dnssec)
+
+	in, _, err := client.ExchangeContext(ctx, msg, server)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Truncated && client.Net != "tcp" && client.Net != "tcp-tls" {
+		tcpClient := *client
+		tcpClient.Net = "tcp"
+		if in, _, err = tcpClient.ExchangeContext(ctx, msg, server); err != nil {
+			return nil, err
+		}
+	}
+
+	if in.Rcode == dns.RcodeNameError {
+		return nil, nil
+	}
+	if in.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("dns: query for %s: %s", host, dns.RcodeToString[in.Rcode])
+	}
+
+	return in, nil
+}
+
+// txtRecordsAndTTL extracts the TXT strings and minimum TTL across all TXT
+// answers in a successful response.
+func txtRecordsAndTTL(in *dns.Msg) ([]string, time.Duration, error) {
+	var records []string
+	minTTL := uint32(math.MaxUint32)
+	for _, rr := range in.Answer {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+		records = append(records, txt.Txt...)
+		if rr.Header().Ttl < minTTL {
+			minTTL = rr.Header().Ttl
+		}
+	}
+
+	if len(records) == 0 {
+		minTTL = 0
+	}
+
+	return records, time.Duration(minTTL) * time.Second, nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startTestDNSServer runs a miekg/dns server on a random local UDP port that
+// always answers with resp, closing it when the test ends, and returns its
+// address for exchangeTXT to query.
+func startTestDNSServer(t *testing.T, resp func(*dns.Msg) *dns.Msg) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		_ = w.WriteMsg(resp(req))
+	})}
+
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return pc.LocalAddr().String()
+}
+
+func TestExchangeTXTReturnsNilForNXDOMAIN(t *testing.T) {
+	addr := startTestDNSServer(t, func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetRcode(req, dns.RcodeNameError)
+		return resp
+	})
+
+	in, err := exchangeTXT(context.Background(), &dns.Client{Timeout: time.Second}, addr, "missing.example.com", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if in != nil {
+		t.Fatalf("expected nil message for NXDOMAIN, got %v", in)
+	}
+}
+
+func TestExchangeTXTReturnsErrorForServerFailure(t *testing.T) {
+	addr := startTestDNSServer(t, func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetRcode(req, dns.RcodeServerFailure)
+		return resp
+	})
+
+	_, err := exchangeTXT(context.Background(), &dns.Client{Timeout: time.Second}, addr, "example.com", false)
+	if err == nil {
+		t.Fatal("expected an error for a non-success rcode")
+	}
+}
+
+func TestExchangeTXTReturnsMessageOnSuccess(t *testing.T) {
+	addr := startTestDNSServer(t, func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = []dns.RR{txtRR("example.com.", 60, "hello")}
+		return resp
+	})
+
+	in, err := exchangeTXT(context.Background(), &dns.Client{Timeout: time.Second}, addr, "example.com", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if in == nil {
+		t.Fatal("expected a non-nil message on success")
+	}
+
+	records, ttl, err := txtRecordsAndTTL(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"hello"}; !stringSlicesEqual(records, want) {
+		t.Fatalf("expected records %v, got %v", want, records)
+	}
+	if ttl != 60*time.Second {
+		t.Fatalf("expected ttl 60s, got %v", ttl)
+	}
+}
+
+// startTestDNSServerUDPAndTCP runs a miekg/dns server on the same local port
+// over both UDP and TCP, so tests can exercise exchangeTXT's truncate-then-
+// retry-over-TCP path: udpResp answers the UDP query, tcpResp answers the
+// retry.
+func startTestDNSServerUDPAndTCP(t *testing.T, udpResp, tcpResp func(*dns.Msg) *dns.Msg) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen udp: %v", err)
+	}
+	_, port, err := net.SplitHostPort(pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to parse udp addr: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		t.Fatalf("failed to listen tcp: %v", err)
+	}
+
+	tcpReady := make(chan struct{})
+	udpServer := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		_ = w.WriteMsg(udpResp(req))
+	})}
+	tcpServer := &dns.Server{Listener: ln, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		_ = w.WriteMsg(tcpResp(req))
+	}), NotifyStartedFunc: func() { close(tcpReady) }}
+
+	go func() { _ = udpServer.ActivateAndServe() }()
+	go func() { _ = tcpServer.ActivateAndServe() }()
+	t.Cleanup(func() {
+		_ = udpServer.Shutdown()
+		_ = tcpServer.Shutdown()
+	})
+	<-tcpReady
+
+	return pc.LocalAddr().String()
+}
+
+func txtRR(name string, ttl uint32, value string) *dns.TXT {
+	return &dns.TXT{
+		Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl},
+		Txt: []string{value},
+	}
+}
+
+func TestExchangeTXTRetriesOverTCPWhenTruncated(t *testing.T) {
+	// A record set too big for a single 512-byte UDP response -- exactly
+	// what forces the TCP retry this test exercises.
+	full := []string{strings.Repeat("a", 255), strings.Repeat("b", 255), strings.Repeat("c", 255)}
+
+	addr := startTestDNSServerUDPAndTCP(t,
+		func(req *dns.Msg) *dns.Msg {
+			resp := new(dns.Msg)
+			resp.SetReply(req)
+			resp.Truncated = true
+			return resp
+		},
+		func(req *dns.Msg) *dns.Msg {
+			resp := new(dns.Msg)
+			resp.SetReply(req)
+			resp.Answer = []dns.RR{&dns.TXT{
+				Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+				Txt: full,
+			}}
+			return resp
+		},
+	)
+
+	in, err := exchangeTXT(context.Background(), &dns.Client{Timeout: time.Second}, addr, "example.com", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, _, err := txtRecordsAndTTL(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !stringSlicesEqual(records, full) {
+		t.Fatalf("expected the full TCP-retried answer, got %v", records)
+	}
+}
+
+func TestExchangeTXTAdvertisesEDNS0ForPlainQueries(t *testing.T) {
+	var gotBufSize uint16
+	addr := startTestDNSServer(t, func(req *dns.Msg) *dns.Msg {
+		if opt := req.IsEdns0(); opt != nil {
+			gotBufSize = opt.UDPSize()
+		}
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		return resp
+	})
+
+	_, err := exchangeTXT(context.Background(), &dns.Client{Timeout: time.Second}, addr, "example.com", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotBufSize != 4096 {
+		t.Fatalf("expected a 4096-byte EDNS0 buffer advertised, got %d", gotBufSize)
+	}
+}
+
+func TestTxtRecordsAndTTLReturnsTrueMinimumEvenWhenZeroComesFirst(t *testing.T) {
+	in := new(dns.Msg)
+	in.Answer = []dns.RR{
+		txtRR("example.com.", 0, "zero-ttl"),
+		txtRR("example.com.", 300, "longer-ttl"),
+	}
+
+	records, ttl, err := txtRecordsAndTTL(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"zero-ttl", "longer-ttl"}; !stringSlicesEqual(records, want) {
+		t.Fatalf("expected records %v, got %v", want, records)
+	}
+	if ttl != 0 {
+		t.Fatalf("expected minimum ttl 0, got %v", ttl)
+	}
+}
+
+func TestTxtRecordsAndTTLPicksSmallestAcrossAllAnswers(t *testing.T) {
+	in := new(dns.Msg)
+	in.Answer = []dns.RR{
+		txtRR("example.com.", 300, "a"),
+		txtRR("example.com.", 60, "b"),
+		txtRR("example.com.", 120, "c"),
+	}
+
+	_, ttl, err := txtRecordsAndTTL(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl != 60*time.Second {
+		t.Fatalf("expected minimum ttl 60s, got %v", ttl)
+	}
+}
+
+func TestTxtRecordsAndTTLIgnoresNonTXTAnswers(t *testing.T) {
+	in := new(dns.Msg)
+	in.Answer = []dns.RR{
+		&dns.A{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30}},
+		txtRR("example.com.", 90, "only-txt"),
+	}
+
+	records, ttl, err := txtRecordsAndTTL(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"only-txt"}; !stringSlicesEqual(records, want) {
+		t.Fatalf("expected records %v, got %v", want, records)
+	}
+	if ttl != 90*time.Second {
+		t.Fatalf("expected ttl 90s, got %v", ttl)
+	}
+}
+
+func TestTxtRecordsAndTTLReturnsZeroForEmptyAnswer(t *testing.T) {
+	in := new(dns.Msg)
+
+	records, ttl, err := txtRecordsAndTTL(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if records != nil {
+		t.Fatalf("expected nil records, got %v", records)
+	}
+	if ttl != 0 {
+		t.Fatalf("expected ttl 0 for no answers, got %v", ttl)
+	}
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startTestDNSSECServer runs a miekg/dns server over DNS-over-TLS on a
+// random local port, backed by an ephemeral self-signed certificate, so
+// tests can exercise dnssecResolver's authenticated transport. It returns
+// the server's address and a client-side tls.Config that skips certificate
+// verification so the self-signed certificate is accepted -- production code
+// instead verifies against the configured server name, see
+// newDNSSECResolver.
+func startTestDNSSECServer(t *testing.T, resp func(*dns.Msg) *dns.Msg) (string, *tls.Config) {
+	t.Helper()
+
+	cert := generateSelfSignedCert(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("failed to listen tls: %v", err)
+	}
+
+	server := &dns.Server{Listener: ln, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		_ = w.WriteMsg(resp(req))
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return ln.Addr().String(), &tls.Config{InsecureSkipVerify: true}
+}
+
+func generateSelfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// dnssecResolverWithTLSConfig builds a dnssecResolver like newDNSSECResolver,
+// but with a caller-supplied tls.Config so tests can point it at a server
+// certificate that isn't signed by a public CA.
+func dnssecResolverWithTLSConfig(server string, tlsConfig *tls.Config) *dnssecResolver {
+	return &dnssecResolver{
+		server: server,
+		client: &dns.Client{Net: "tcp-tls", Timeout: time.Second, TLSConfig: tlsConfig},
+	}
+}
+
+func TestDNSSECResolverRejectsUnauthenticatedAnswers(t *testing.T) {
+	addr, tlsConfig := startTestDNSSECServer(t, func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.AuthenticatedData = false
+		resp.Answer = []dns.RR{txtRR("example.com.", 60, "hello")}
+		return resp
+	})
+
+	r := dnssecResolverWithTLSConfig(addr, tlsConfig)
+	_, err := r.LookupTXT(context.Background(), "example.com")
+	if !errors.Is(err, errDNSSECValidationFailed) {
+		t.Fatalf("expected errDNSSECValidationFailed, got %v", err)
+	}
+}
+
+func TestDNSSECResolverAcceptsAuthenticatedAnswers(t *testing.T) {
+	addr, tlsConfig := startTestDNSSECServer(t, func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.AuthenticatedData = true
+		resp.Answer = []dns.RR{txtRR("example.com.", 60, "hello")}
+		return resp
+	})
+
+	r := dnssecResolverWithTLSConfig(addr, tlsConfig)
+	records, ttl, err := r.LookupTXTWithTTL(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"hello"}; !stringSlicesEqual(records, want) {
+		t.Fatalf("expected records %v, got %v", want, records)
+	}
+	if ttl != 60*time.Second {
+		t.Fatalf("expected ttl 60s, got %v", ttl)
+	}
+}
+
+func TestDNSSECResolverPassesThroughNXDOMAIN(t *testing.T) {
+	addr, tlsConfig := startTestDNSSECServer(t, func(req *dns.Msg) *dns.Msg {
+		resp := new(dns.Msg)
+		resp.SetRcode(req, dns.RcodeNameError)
+		return resp
+	})
+
+	r := dnssecResolverWithTLSConfig(addr, tlsConfig)
+	records, err := r.LookupTXT(context.Background(), "missing.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if records != nil {
+		t.Fatalf("expected nil records for NXDOMAIN, got %v", records)
+	}
+}
+
+func TestNewDNSSECResolverVerifiesServerCertificate(t *testing.T) {
+	r := newDNSSECResolver(defaultDNSSECServer, defaultDNSSECServerName)
+	if r.client.Net != "tcp-tls" {
+		t.Fatalf("expected a DNS-over-TLS transport, got Net=%q", r.client.Net)
+	}
+	if r.client.TLSConfig == nil || r.client.TLSConfig.InsecureSkipVerify {
+		t.Fatal("expected the resolver to verify the server's certificate")
+	}
+	if r.client.TLSConfig.ServerName != defaultDNSSECServerName {
+		t.Fatalf("expected ServerName %q, got %q", defaultDNSSECServerName, r.client.TLSConfig.ServerName)
+	}
+}
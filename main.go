@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"log"
+	"log/slog"
 	"mime"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -40,7 +43,9 @@ Info: https://txtweb.lefelys.com
 `
 
 func resolveTXTRecord(ctx context.Context, resolver txtResolver, name string) ([]string, error) {
+	start := time.Now()
 	records, err := resolver.LookupTXT(ctx, name)
+	observerFromContext(ctx).addDNSLookupDuration(time.Since(start))
 	if err != nil {
 		var dnsErr *net.DNSError
 		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
@@ -136,6 +141,18 @@ func wrapHTML(content, align, maxWidth, bgColor, fgColor string) string {
 		"</div></body></html>"
 }
 
+// writeLookupError translates a TXT lookup failure into the right HTTP
+// status: a failed DNSSEC validation is a distinct, more specific failure
+// than an ordinary DNS error, so it gets its own 502 rather than a generic
+// 500.
+func writeLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errDNSSECValidationFailed) {
+		http.Error(w, "DNSSEC validation failed", http.StatusBadGateway)
+		return
+	}
+	http.Error(w, "DNS lookup failed", http.StatusInternalServerError)
+}
+
 func extractHostname(host string) string {
 	host = strings.TrimSpace(host)
 	if host == "" {
@@ -156,6 +173,32 @@ func extractHostname(host string) string {
 
 func newHandler(resolver txtResolver) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sr := &statusRecorder{ResponseWriter: w}
+		w = sr
+
+		obs := &requestObserver{}
+		ctx := withRequestObserver(r.Context(), obs)
+		r = r.WithContext(ctx)
+
+		var hostname string
+		var recordCount int
+
+		defer func() {
+			dnsLookupDurationSeconds.Observe(obs.dnsLookupDuration.Seconds())
+			responseSizeBytes.Observe(float64(sr.bytes))
+			requestsTotal.WithLabelValues(strconv.Itoa(sr.status)).Inc()
+
+			slog.Info("request",
+				"host", hostname,
+				"records", recordCount,
+				"cache_hits", obs.cacheHits,
+				"cache_misses", obs.cacheMisses,
+				"bytes", sr.bytes,
+				"status", sr.status,
+				"dns_lookup_duration", obs.dnsLookupDuration,
+			)
+		}()
+
 		w.Header().Set(poweredByHeaderName, poweredByHeaderValue)
 
 		hostHeader := r.Host
@@ -163,31 +206,62 @@ func newHandler(resolver txtResolver) http.Handler {
 			hostHeader = r.URL.Host
 		}
 
-		hostname := extractHostname(hostHeader)
+		hostname = extractHostname(hostHeader)
 		if hostname == "" {
 			http.Error(w, "Missing Host header", http.StatusBadRequest)
 			return
 		}
 
-		txtRecords, err := resolveTXTRecord(r.Context(), resolver, txtwebRecord+"."+hostname)
+		cfgRecord, err := lookupFirstTXTRecord(r.Context(), resolver, txtwebConfigRecord, hostname)
 		if err != nil {
-			http.Error(w, "DNS lookup failed", http.StatusInternalServerError)
+			writeLookupError(w, err)
 			return
 		}
 
+		cfg := parseTXTWebConfig(cfgRecord)
+
+		var txtRecords []string
+		chunkHostname := hostname
+		if strings.EqualFold(strings.TrimSpace(cfg[pathsConfigKey]), "true") {
+			labels, ok := splitPathLabels(r.URL.Path)
+			if !ok {
+				http.Error(w, "Invalid path", http.StatusBadRequest)
+				return
+			}
+			if len(labels) > pathsMaxDepth(cfg[pathsMaxDepthConfigKey]) {
+				http.Error(w, "Path too deep", http.StatusBadRequest)
+				return
+			}
+
+			if len(labels) > 0 {
+				pathRecords, err := resolveTXTRecord(r.Context(), resolver, pathRecordName(hostname, labels))
+				if err != nil {
+					writeLookupError(w, err)
+					return
+				}
+				if len(pathRecords) > 0 {
+					txtRecords = pathRecords
+					chunkHostname = pathsQualifiedHost(hostname, labels)
+				}
+			}
+		}
+
 		if len(txtRecords) == 0 {
-			w.WriteHeader(http.StatusNotFound)
-			_, _ = w.Write([]byte(indexHeader))
-			return
+			rootRecords, err := resolveTXTRecord(r.Context(), resolver, txtwebRecord+"."+hostname)
+			if err != nil {
+				writeLookupError(w, err)
+				return
+			}
+			txtRecords = rootRecords
+			chunkHostname = hostname
 		}
 
-		cfgRecord, err := lookupFirstTXTRecord(r.Context(), resolver, txtwebConfigRecord, hostname)
-		if err != nil {
-			http.Error(w, "DNS lookup failed", http.StatusInternalServerError)
+		if len(txtRecords) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(indexHeader))
 			return
 		}
 
-		cfg := parseTXTWebConfig(cfgRecord)
 		contentType := cfg["content-type"]
 		wrapValue := cfg["html-wrap"]
 		alignValue := cfg["html-align"]
@@ -195,7 +269,42 @@ func newHandler(resolver txtResolver) http.Handler {
 		bgColorValue := cfg["html-bg"]
 		fgColorValue := cfg["html-fg"]
 
-		responseBody := strings.Join(txtRecords, "\n")
+		allRecords := orderRecords(txtRecords)
+		if chunkCount := parseChunkCount(cfg[chunksConfigKey]); chunkCount > 0 {
+			chunks, err := fetchChunks(r.Context(), resolver, chunkHostname, chunkCount)
+			if err != nil {
+				writeLookupError(w, err)
+				return
+			}
+			for _, chunk := range chunks {
+				allRecords = append(allRecords, orderRecords(chunk)...)
+			}
+		}
+		recordCount = len(allRecords)
+
+		responseBody := strings.Join(allRecords, "\n")
+
+		tc := transformContext{ctx: r.Context(), resolver: resolver, hostname: hostname, request: r}
+		for _, t := range buildBodyPipeline(cfg) {
+			transformed, err := t.transform(tc, responseBody)
+			if err != nil {
+				http.Error(w, "content transform failed", http.StatusInternalServerError)
+				return
+			}
+			responseBody = transformed
+		}
+
+		// Checked after the transform pipeline, not before: a template can
+		// call "txt" to pull in further TXT lookups, so the raw joined
+		// records are not an upper bound on the final body size.
+		if len(responseBody) > maxBodyBytes() {
+			http.Error(w, "content too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if strings.EqualFold(strings.TrimSpace(cfg[formatConfigKey]), formatMarkdown) && contentType == "" {
+			contentType = defaultWrappedContentType
+		}
+
 		if strings.EqualFold(strings.TrimSpace(wrapValue), "true") {
 			responseBody = wrapHTML(
 				responseBody,
@@ -214,6 +323,15 @@ func newHandler(resolver txtResolver) http.Handler {
 		}
 
 		w.Header().Set("content-type", contentType)
+
+		if enc, encName := selectEncoding(cfg[encodingConfigKey], r.Header.Get("Accept-Encoding")); enc != nil {
+			w.Header().Set("Content-Encoding", encName)
+			wc := enc.wrap(w)
+			_, _ = wc.Write([]byte(responseBody))
+			_ = wc.Close()
+			return
+		}
+
 		_, _ = w.Write([]byte(responseBody))
 	})
 }
@@ -228,13 +346,55 @@ func runWith(resolver txtResolver, serve serveFunc) error {
 		}
 	}
 
+	switch {
+	case envBool(envDNSSECRequired, false):
+		resolver = newDNSSECResolver(
+			envOr(envDNSSECServer, defaultDNSSECServer),
+			envOr(envDNSSECServerName, defaultDNSSECServerName),
+		)
+	case os.Getenv(envDNSServer) != "":
+		resolver = newDNSResolver(os.Getenv(envDNSServer))
+	}
+
+	cacheCfg := loadCacheConfigFromEnv()
+	if cacheCfg.enabled {
+		resolver = newCachingResolver(resolver, cacheCfg)
+	}
+
+	if adminAddr := os.Getenv(envAdminAddr); adminAddr != "" {
+		go func() {
+			if err := serveAdmin(adminAddr); err != nil {
+				slog.Error("admin server stopped", "err", err)
+			}
+		}()
+	}
+
+	handler := newHandler(resolver)
+
+	tlsCfg := loadTLSConfigFromEnv()
+	if tlsCfg.enabled {
+		manager := newAutocertManager(tlsCfg, resolver)
+
+		go func() {
+			if err := serveTLS(handler, tlsCfg, manager); err != nil {
+				slog.Error("TLS server stopped", "err", err)
+			}
+		}()
+
+		httpHandler := handler
+		if tlsCfg.redirectHTTP {
+			httpHandler = redirectToHTTPSHandler()
+		}
+		handler = manager.HTTPHandler(httpHandler)
+	}
+
 	server := &http.Server{
 		Addr:              ":80",
-		Handler:           newHandler(resolver),
+		Handler:           handler,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
-	log.Printf("listening on %s", server.Addr)
+	slog.Info("listening", "addr", server.Addr, "entrypoint", "http")
 	if err := serve(server); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"compress/gzip"
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -165,3 +167,300 @@ func TestHandlerReturnsClownEmojiFromEscapedText(t *testing.T) {
 		t.Fatalf("expected clown emoji output %q, got %q", "A! 🤡", got)
 	}
 }
+
+func TestHandlerAssemblesOrderedChunks(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":     {"02|root-b", "01|root-a"},
+			"_txtweb_cfg.example.com": {"chunks=2"},
+			"_txtweb1.example.com":    {"01|chunk1-a", "02|chunk1-b"},
+			"_txtweb2.example.com":    {"chunk2-only"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	want := "root-a\nroot-b\nchunk1-a\nchunk1-b\nchunk2-only"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("expected body %q, got %q", want, got)
+	}
+}
+
+func TestHandlerReturns413WhenBodyExceedsCap(t *testing.T) {
+	t.Setenv(envMaxBodyBytes, "4")
+
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com": {"way too long for the configured cap"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413, got %d", rec.Code)
+	}
+}
+
+func TestHandlerReturns413WhenTemplateExpansionExceedsCap(t *testing.T) {
+	t.Setenv(envMaxBodyBytes, "1024")
+
+	big := strings.Repeat("x", 900)
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":     {strings.Repeat(`{{txt "_txtweb_big"}}`, 5)},
+			"_txtweb_cfg.example.com": {"template=true"},
+			"_txtweb_big.example.com": {big},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413, got %d (body %q)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlerServesNestedPathRecord(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":         {"root page"},
+			"_txtweb_cfg.example.com":     {"paths=true"},
+			"_txtweb.foo.bar.example.com": {"nested page"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/foo/bar/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Body.String(); got != "nested page" {
+		t.Fatalf("expected body %q, got %q", "nested page", got)
+	}
+}
+
+func TestHandlerFallsBackToRootWhenPathRecordMissing(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":     {"root page"},
+			"_txtweb_cfg.example.com": {"paths=true"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/missing", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Body.String(); got != "root page" {
+		t.Fatalf("expected body %q, got %q", "root page", got)
+	}
+}
+
+func TestHandlerScopesChunksToTheResolvedPath(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":        {"root page"},
+			"_txtweb1.example.com":       {"root-chunk-leaked-onto-every-page"},
+			"_txtweb_cfg.example.com":    {"paths=true;chunks=1"},
+			"_txtweb.about.example.com":  {"about page"},
+			"_txtweb1.about.example.com": {"about-chunk"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/about", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	want := "about page\nabout-chunk"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("expected body %q, got %q", want, got)
+	}
+}
+
+func TestHandlerReturns400ForInvalidPathLabel(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":     {"root page"},
+			"_txtweb_cfg.example.com": {"paths=true"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/foo_bar!", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestHandlerRendersMarkdown(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":     {"# hello"},
+			"_txtweb_cfg.example.com": {"format=markdown"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("content-type"); got != defaultWrappedContentType {
+		t.Fatalf("expected content-type %q, got %q", defaultWrappedContentType, got)
+	}
+	if !strings.Contains(rec.Body.String(), "<h1>hello</h1>") {
+		t.Fatalf("expected rendered markdown, got %q", rec.Body.String())
+	}
+}
+
+func TestHandlerRendersTemplate(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":     {"host: {{host}} path: {{path}}"},
+			"_txtweb_cfg.example.com": {"template=true"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/hi", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	want := "host: example.com path: /hi"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("expected body %q, got %q", want, got)
+	}
+}
+
+func TestHandlerGzipsResponseWhenAccepted(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":     {"hello, world"},
+			"_txtweb_cfg.example.com": {"encoding=gzip"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected Content-Encoding %q, got %q", "gzip", got)
+	}
+
+	gzr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("failed to open gzip reader: %v", err)
+	}
+	body, err := io.ReadAll(gzr)
+	if err != nil {
+		t.Fatalf("failed to read gzipped body: %v", err)
+	}
+	if got := string(body); got != "hello, world" {
+		t.Fatalf("expected decompressed body %q, got %q", "hello, world", got)
+	}
+}
+
+func TestHandlerDoesNotGzipWhenExplicitlyRefused(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":     {"hello, world"},
+			"_txtweb_cfg.example.com": {"encoding=gzip"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("expected no Content-Encoding, got %q", got)
+	}
+	if got := rec.Body.String(); got != "hello, world" {
+		t.Fatalf("expected plain body %q, got %q", "hello, world", got)
+	}
+}
+
+func TestHandlerReturns400WhenPathExceedsMaxDepth(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com":     {"root page"},
+			"_txtweb_cfg.example.com": {"paths=true;paths-max-depth=1"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/a/b", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestHandlerReturns502WhenDNSSECValidationFails(t *testing.T) {
+	handler := newHandler(&fakeResolver{
+		errs: map[string]error{
+			"_txtweb_cfg.example.com": errDNSSECValidationFailed,
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandlerOnlyServesMetrics(t *testing.T) {
+	handler := newAdminHandler()
+
+	metricsReq := httptest.NewRequest(http.MethodGet, "http://admin/metrics", nil)
+	metricsRec := httptest.NewRecorder()
+	handler.ServeHTTP(metricsRec, metricsReq)
+	if metricsRec.Code != http.StatusOK {
+		t.Fatalf("expected /metrics to return 200, got %d", metricsRec.Code)
+	}
+
+	siteReq := httptest.NewRequest(http.MethodGet, "http://admin/", nil)
+	siteRec := httptest.NewRecorder()
+	handler.ServeHTTP(siteRec, siteReq)
+	if siteRec.Code != http.StatusNotFound {
+		t.Fatalf("expected admin listener to refuse site content, got %d", siteRec.Code)
+	}
+}
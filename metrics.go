@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const envAdminAddr = "TXTWEB_ADMIN_ADDR"
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "txtweb_requests_total",
+		Help: "Total number of HTTP requests served, by response status code.",
+	}, []string{"status"})
+
+	dnsLookupDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "txtweb_dns_lookup_duration_seconds",
+		Help:    "Latency of the DNS TXT lookups performed to serve a request.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	cacheHitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "txtweb_cache_hits_total",
+		Help: "Total number of TXT lookups served from cache.",
+	})
+
+	cacheMissesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "txtweb_cache_misses_total",
+		Help: "Total number of TXT lookups that missed the cache.",
+	})
+
+	responseSizeBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "txtweb_response_size_bytes",
+		Help:    "Size of the response body written to clients.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		requestsTotal,
+		dnsLookupDurationSeconds,
+		cacheHitsTotal,
+		cacheMissesTotal,
+		responseSizeBytes,
+	)
+}
+
+// newAdminHandler serves only /metrics. Every other path is refused, so the
+// admin listener -- meant to be bound to a private interface -- can never
+// be mistaken for, or used to serve, site content.
+func newAdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		http.NotFound(w, nil)
+	})
+	return mux
+}
+
+// serveAdmin starts the admin listener carrying the /metrics endpoint. It
+// is off by default and only started when TXTWEB_ADMIN_ADDR is set,
+// following the entrypoint-separation pattern used by reverse proxies like
+// Traefik.
+func serveAdmin(addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newAdminHandler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	slog.Info("listening", "addr", server.Addr, "entrypoint", "admin")
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
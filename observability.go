@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type requestObserverKey struct{}
+
+// requestObserver accumulates the per-lookup facts a single request racks
+// up (DNS latency, cache hits/misses) so they can be logged as one
+// structured event once the response is written, rather than scattered
+// across several log lines.
+type requestObserver struct {
+	mu                sync.Mutex
+	dnsLookupDuration time.Duration
+	cacheHits         int
+	cacheMisses       int
+}
+
+func withRequestObserver(ctx context.Context, obs *requestObserver) context.Context {
+	return context.WithValue(ctx, requestObserverKey{}, obs)
+}
+
+func observerFromContext(ctx context.Context) *requestObserver {
+	obs, _ := ctx.Value(requestObserverKey{}).(*requestObserver)
+	return obs
+}
+
+func (o *requestObserver) addDNSLookupDuration(d time.Duration) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	o.dnsLookupDuration += d
+	o.mu.Unlock()
+}
+
+func (o *requestObserver) recordCacheResult(hit bool) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	if hit {
+		o.cacheHits++
+	} else {
+		o.cacheMisses++
+	}
+	o.mu.Unlock()
+}
+
+// statusRecorder wraps an http.ResponseWriter to capture the status code
+// and byte count ultimately written, for access logging and metrics.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	pathsConfigKey         = "paths"
+	pathsMaxDepthConfigKey = "paths-max-depth"
+	defaultPathsMaxDepth   = 3
+)
+
+// dnsLabelPattern matches a single valid DNS label: letters, digits and
+// hyphens. Path segments that don't match this are rejected rather than
+// silently dropped, since they could never resolve to a real record anyway.
+var dnsLabelPattern = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+
+// splitPathLabels splits a URL path into DNS labels, most-significant-first
+// (so "/foo/bar" becomes ["foo", "bar"]), ignoring a leading/trailing slash.
+// It returns false if any segment contains characters that can't appear in
+// a DNS label.
+func splitPathLabels(path string) ([]string, bool) {
+	trimmed := strings.Trim(path, "/")
+	if trimmed == "" {
+		return nil, true
+	}
+
+	segments := strings.Split(trimmed, "/")
+	labels := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		if !dnsLabelPattern.MatchString(segment) {
+			return nil, false
+		}
+		labels = append(labels, segment)
+	}
+
+	return labels, true
+}
+
+// pathsMaxDepth reads the "paths-max-depth" config value, falling back to
+// defaultPathsMaxDepth when absent or invalid.
+func pathsMaxDepth(value string) int {
+	if value == "" {
+		return defaultPathsMaxDepth
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return defaultPathsMaxDepth
+	}
+	return n
+}
+
+// pathsQualifiedHost returns the DNS suffix a path's own records -- and
+// anything scoped under it, like per-path chunks -- are rooted at, e.g.
+// labels ["foo", "bar"] and hostname "example.com" becomes
+// "foo.bar.example.com". With no labels it's just the hostname.
+func pathsQualifiedHost(hostname string, labels []string) string {
+	if len(labels) == 0 {
+		return hostname
+	}
+	return strings.Join(labels, ".") + "." + hostname
+}
+
+// pathRecordName builds the TXT record name for a path, e.g. labels
+// ["foo", "bar"] and hostname "example.com" becomes
+// "_txtweb.foo.bar.example.com".
+func pathRecordName(hostname string, labels []string) string {
+	return txtwebRecord + "." + pathsQualifiedHost(hostname, labels)
+}
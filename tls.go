@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	envTLSEnabled       = "TXTWEB_TLS_ENABLED"
+	envTLSRedirectHTTP  = "TXTWEB_TLS_REDIRECT_HTTP"
+	envACMECacheDir     = "TXTWEB_ACME_CACHE_DIR"
+	envACMEEmail        = "TXTWEB_ACME_EMAIL"
+	envACMEStaging      = "TXTWEB_ACME_STAGING"
+	defaultACMECacheDir = "/var/cache/txtweb/autocert"
+
+	tlsAddr = ":443"
+)
+
+// tlsConfig holds the ACME/TLS knobs txtweb reads from the environment. Every
+// field has a zero-config default so the binary keeps serving plain HTTP on
+// port 80 unless an operator opts in.
+type tlsConfig struct {
+	enabled      bool
+	redirectHTTP bool
+	cacheDir     string
+	email        string
+	staging      bool
+}
+
+func loadTLSConfigFromEnv() tlsConfig {
+	return tlsConfig{
+		enabled:      envBool(envTLSEnabled, false),
+		redirectHTTP: envBool(envTLSRedirectHTTP, false),
+		cacheDir:     envOr(envACMECacheDir, defaultACMECacheDir),
+		email:        os.Getenv(envACMEEmail),
+		staging:      envBool(envACMEStaging, false),
+	}
+}
+
+// acmeHostPolicy only allows autocert to request a certificate for hostnames
+// that have opted in via DNS: it requires "_txtweb.<hostname>" to resolve to
+// a TXT record, reusing the same lookup the handler uses to serve content.
+func acmeHostPolicy(resolver txtResolver) autocert.HostPolicy {
+	return func(ctx context.Context, host string) error {
+		records, err := resolveTXTRecord(ctx, resolver, txtwebRecord+"."+host)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return errHostNotOptedIn(host)
+		}
+		return nil
+	}
+}
+
+type errHostNotOptedIn string
+
+func (e errHostNotOptedIn) Error() string {
+	return "host " + string(e) + " has no " + txtwebRecord + " TXT record, refusing to issue a certificate"
+}
+
+func newAutocertManager(cfg tlsConfig, resolver txtResolver) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cfg.cacheDir),
+		HostPolicy: acmeHostPolicy(resolver),
+		Email:      cfg.email,
+	}
+	if cfg.staging {
+		m.Client = &acme.Client{DirectoryURL: "https://acme-staging-v02.api.letsencrypt.org/directory"}
+	}
+	return m
+}
+
+func redirectToHTTPSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hostname := extractHostname(r.Host)
+		http.Redirect(w, r, "https://"+hostname+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// serveTLS starts the HTTPS listener with certificates provisioned on demand
+// via ACME. It runs alongside the plain HTTP server started by runWith, which
+// only calls it when TXTWEB_TLS_ENABLED is set.
+func serveTLS(handler http.Handler, cfg tlsConfig, m *autocert.Manager) error {
+	server := &http.Server{
+		Addr:              tlsAddr,
+		Handler:           handler,
+		TLSConfig:         m.TLSConfig(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	slog.Info("listening", "addr", server.Addr, "entrypoint", "tls")
+	if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
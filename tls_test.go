@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAcmeHostPolicyAllowsOptedInHost(t *testing.T) {
+	policy := acmeHostPolicy(&fakeResolver{
+		records: map[string][]string{
+			"_txtweb.example.com": {"hello"},
+		},
+	})
+
+	if err := policy(context.Background(), "example.com"); err != nil {
+		t.Fatalf("expected host to be allowed, got error: %v", err)
+	}
+}
+
+func TestAcmeHostPolicyRejectsHostWithoutTXTRecord(t *testing.T) {
+	policy := acmeHostPolicy(&fakeResolver{})
+
+	err := policy(context.Background(), "example.com")
+	var notOptedIn errHostNotOptedIn
+	if !errors.As(err, &notOptedIn) {
+		t.Fatalf("expected errHostNotOptedIn, got %v", err)
+	}
+}
+
+func TestAcmeHostPolicyPropagatesLookupErrors(t *testing.T) {
+	wantErr := errors.New("lookup failed")
+	policy := acmeHostPolicy(&fakeResolver{
+		errs: map[string]error{
+			"_txtweb.example.com": wantErr,
+		},
+	})
+
+	if err := policy(context.Background(), "example.com"); !errors.Is(err, wantErr) {
+		t.Fatalf("expected error %v, got %v", wantErr, err)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/yuin/goldmark"
+)
+
+const (
+	formatConfigKey   = "format"
+	templateConfigKey = "template"
+	encodingConfigKey = "encoding"
+	formatMarkdown    = "markdown"
+	encodingGzip      = "gzip"
+	encodingBrotli    = "br"
+)
+
+// transformContext carries the request-scoped values available to body
+// transformers, e.g. the template FuncMap's "txt" function.
+type transformContext struct {
+	ctx      context.Context
+	resolver txtResolver
+	hostname string
+	request  *http.Request
+}
+
+// bodyTransformer mutates the response body before it's written, mirroring
+// the middleware-composition pattern: each one is self-contained and
+// registered once, so adding a new transform never touches newHandler.
+type bodyTransformer interface {
+	transform(tc transformContext, body string) (string, error)
+}
+
+// bodyTransformerRegistry maps a transform's name to its implementation.
+// buildBodyPipeline looks steps up here by name; it only selects the names
+// it checks the config for, so a new transformer also needs its config
+// check added there.
+var bodyTransformerRegistry = map[string]bodyTransformer{
+	templateConfigKey: templateTransformer{},
+	formatMarkdown:    markdownTransformer{},
+}
+
+// buildBodyPipeline selects and orders the transformers a request's config
+// asks for. Template runs first so its output (which may itself be
+// Markdown source) is rendered by any later step.
+func buildBodyPipeline(cfg map[string]string) []bodyTransformer {
+	var pipeline []bodyTransformer
+
+	if strings.EqualFold(strings.TrimSpace(cfg[templateConfigKey]), "true") {
+		pipeline = append(pipeline, bodyTransformerRegistry[templateConfigKey])
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg[formatConfigKey]), formatMarkdown) {
+		pipeline = append(pipeline, bodyTransformerRegistry[formatMarkdown])
+	}
+
+	return pipeline
+}
+
+type markdownTransformer struct{}
+
+func (markdownTransformer) transform(_ transformContext, body string) (string, error) {
+	var buf bytes.Buffer
+	if err := goldmark.Convert([]byte(body), &buf); err != nil {
+		return "", fmt.Errorf("render markdown: %w", err)
+	}
+	return buf.String(), nil
+}
+
+type templateTransformer struct{}
+
+func (templateTransformer) transform(tc transformContext, body string) (string, error) {
+	tmpl, err := template.New("txtweb").Funcs(template.FuncMap{
+		"now":  time.Now,
+		"host": func() string { return tc.hostname },
+		"path": func() string { return tc.request.URL.Path },
+		"txt": func(record string) (string, error) {
+			value, err := lookupFirstTXTRecord(tc.ctx, tc.resolver, record, tc.hostname)
+			if err != nil {
+				return "", err
+			}
+			return value, nil
+		},
+	}).Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return "", fmt.Errorf("execute template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// encodingTransformer wraps the response writer to compress the body,
+// honoring Accept-Encoding. Unlike bodyTransformer, it operates on the
+// writer rather than the body string, since compression happens on the
+// wire, not on the content.
+type encodingTransformer interface {
+	wrap(w io.Writer) io.WriteCloser
+}
+
+var encodingRegistry = map[string]encodingTransformer{
+	encodingGzip:   gzipEncoding{},
+	encodingBrotli: brotliEncoding{},
+}
+
+type gzipEncoding struct{}
+
+func (gzipEncoding) wrap(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+
+type brotliEncoding struct{}
+
+func (brotliEncoding) wrap(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }
+
+// selectEncoding picks the configured encoding, but only if the client
+// actually advertises support for it via Accept-Encoding.
+func selectEncoding(configured, acceptEncoding string) (encodingTransformer, string) {
+	configured = strings.TrimSpace(configured)
+	if configured == "" {
+		return nil, ""
+	}
+
+	enc, ok := encodingRegistry[configured]
+	if !ok || !acceptsEncoding(acceptEncoding, configured) {
+		return nil, ""
+	}
+	return enc, configured
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header value lists
+// coding as acceptable. It parses the header's comma-separated tokens and
+// ";q=" weights rather than substring-matching the raw header, so e.g.
+// "gzip;q=0" (an explicit refusal) isn't mistaken for acceptance.
+func acceptsEncoding(acceptEncoding, coding string) bool {
+	for _, token := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(token, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), coding) {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(name) != "q" {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		return q > 0
+	}
+	return false
+}
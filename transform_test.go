@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		coding         string
+		want           bool
+	}{
+		{"plain token", "gzip", "gzip", true},
+		{"among several", "deflate, gzip, br", "gzip", true},
+		{"case insensitive", "GZIP", "gzip", true},
+		{"explicit refusal", "gzip;q=0", "gzip", false},
+		{"explicit refusal among several", "br;q=1, gzip;q=0", "gzip", false},
+		{"zero with decimals", "gzip;q=0.0", "gzip", false},
+		{"low but nonzero weight still accepts", "gzip;q=0.1", "gzip", true},
+		{"not listed", "br", "gzip", false},
+		{"empty header", "", "gzip", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := acceptsEncoding(tt.acceptEncoding, tt.coding); got != tt.want {
+				t.Fatalf("acceptsEncoding(%q, %q) = %v, want %v", tt.acceptEncoding, tt.coding, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectEncodingRejectsExplicitlyRefusedCoding(t *testing.T) {
+	enc, name := selectEncoding("gzip", "gzip;q=0")
+	if enc != nil || name != "" {
+		t.Fatalf("expected no encoding selected, got %v %q", enc, name)
+	}
+}
+
+func TestSelectEncodingReturnsNilForUnconfiguredEncoding(t *testing.T) {
+	enc, name := selectEncoding("", "gzip")
+	if enc != nil || name != "" {
+		t.Fatalf("expected no encoding selected, got %v %q", enc, name)
+	}
+}